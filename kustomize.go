@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+
+	"sigs.k8s.io/kustomize/api/krusty"
+	ktypes "sigs.k8s.io/kustomize/api/types"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+)
+
+// kustomizeOptions mirrors the `kustomize_options` block on k8s_kustomization.
+type kustomizeOptions struct {
+	loadRestrictor     string
+	enableHelm         bool
+	enableAlphaPlugins bool
+}
+
+// renderKustomization builds target (a local path or a remote URL
+// kustomize's loader understands, e.g. a `github.com/org/repo//overlay?ref=`
+// reference) out of fSys and returns the rendered manifests as a single
+// `---`-separated YAML stream, ready for decodeManifests. Callers building a
+// real kustomization pass filesys.MakeFsOnDisk(); tests can pass an in-memory
+// one instead.
+func renderKustomization(fSys filesys.FileSystem, target string, opts kustomizeOptions) (string, error) {
+	kOpts := krusty.MakeDefaultOptions()
+	kOpts.LoadRestrictions = loadRestrictions(opts.loadRestrictor)
+	kOpts.PluginConfig.HelmConfig.Enabled = opts.enableHelm
+	if opts.enableAlphaPlugins {
+		kOpts.PluginConfig.FnpLoadingOptions.EnableStar = true
+		kOpts.PluginConfig.PluginRestrictions = ktypes.PluginRestrictionsNone
+	}
+
+	k := krusty.MakeKustomizer(kOpts)
+	resMap, err := k.Run(fSys, target)
+	if err != nil {
+		return "", fmt.Errorf("building kustomization %q: %v", target, err)
+	}
+
+	rendered, err := resMap.AsYaml()
+	if err != nil {
+		return "", fmt.Errorf("rendering kustomization %q: %v", target, err)
+	}
+	return string(rendered), nil
+}
+
+func loadRestrictions(restrictor string) ktypes.LoadRestrictions {
+	if restrictor == "none" {
+		return ktypes.LoadRestrictionsNone
+	}
+	return ktypes.LoadRestrictionsRootOnly
+}
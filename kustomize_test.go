@@ -0,0 +1,103 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	ktypes "sigs.k8s.io/kustomize/api/types"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+func TestLoadRestrictions(t *testing.T) {
+	if got := loadRestrictions("none"); got != ktypes.LoadRestrictionsNone {
+		t.Fatalf("loadRestrictions(%q) = %v, want LoadRestrictionsNone", "none", got)
+	}
+	if got := loadRestrictions("rootOnly"); got != ktypes.LoadRestrictionsRootOnly {
+		t.Fatalf("loadRestrictions(%q) = %v, want LoadRestrictionsRootOnly", "rootOnly", got)
+	}
+}
+
+func TestKustomizationTargetRequiresPathOrURL(t *testing.T) {
+	d := schema.TestResourceDataRaw(t, resourceKustomization().Schema, map[string]interface{}{})
+	if _, _, err := kustomizationTarget(d); err == nil {
+		t.Fatalf("expected an error when neither path nor url is set")
+	}
+}
+
+func TestKustomizationTargetPrefersPath(t *testing.T) {
+	d := schema.TestResourceDataRaw(t, resourceKustomization().Schema, map[string]interface{}{
+		"path": "./overlays/prod",
+	})
+	target, opts, err := kustomizationTarget(d)
+	if err != nil {
+		t.Fatalf("kustomizationTarget: %v", err)
+	}
+	if target != "./overlays/prod" {
+		t.Fatalf("target = %q, want %q", target, "./overlays/prod")
+	}
+	if opts.loadRestrictor != "rootOnly" || opts.enableHelm || opts.enableAlphaPlugins {
+		t.Fatalf("got opts %+v, want the rootOnly/no-helm/no-alpha-plugins defaults", opts)
+	}
+}
+
+func TestKustomizationTargetReadsOptions(t *testing.T) {
+	d := schema.TestResourceDataRaw(t, resourceKustomization().Schema, map[string]interface{}{
+		"url": "github.com/org/repo//overlay?ref=main",
+		"kustomize_options": []interface{}{
+			map[string]interface{}{
+				"load_restrictor":      "none",
+				"enable_helm":          true,
+				"enable_alpha_plugins": true,
+			},
+		},
+	})
+	target, opts, err := kustomizationTarget(d)
+	if err != nil {
+		t.Fatalf("kustomizationTarget: %v", err)
+	}
+	if target != "github.com/org/repo//overlay?ref=main" {
+		t.Fatalf("target = %q, want the url", target)
+	}
+	if opts.loadRestrictor != "none" || !opts.enableHelm || !opts.enableAlphaPlugins {
+		t.Fatalf("got opts %+v, want every kustomize_options field threaded through", opts)
+	}
+}
+
+// TestRenderKustomization exercises renderKustomization against an in-memory
+// filesystem, with no real cluster or disk access, to cover the only
+// non-cluster-dependent rendering logic k8s_kustomization adds.
+func TestRenderKustomization(t *testing.T) {
+	fSys := filesys.MakeFsInMemory()
+	if err := fSys.WriteFile("/kustomization.yaml", []byte(`
+resources:
+- configmap.yaml
+`)); err != nil {
+		t.Fatalf("writing kustomization.yaml: %v", err)
+	}
+	if err := fSys.WriteFile("/configmap.yaml", []byte(`
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: example
+data:
+  key: value
+`)); err != nil {
+		t.Fatalf("writing configmap.yaml: %v", err)
+	}
+
+	rendered, err := renderKustomization(fSys, "/", kustomizeOptions{loadRestrictor: "rootOnly"})
+	if err != nil {
+		t.Fatalf("renderKustomization: %v", err)
+	}
+	if !strings.Contains(rendered, "name: example") {
+		t.Fatalf("rendered output missing the ConfigMap: %s", rendered)
+	}
+}
+
+func TestRenderKustomizationMissingKustomizationFile(t *testing.T) {
+	if _, err := renderKustomization(filesys.MakeFsInMemory(), "/", kustomizeOptions{loadRestrictor: "rootOnly"}); err == nil {
+		t.Fatalf("expected an error building a kustomization with no kustomization.yaml")
+	}
+}
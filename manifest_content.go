@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// decodeManifests splits content into one or more Kubernetes objects: YAML
+// documents separated by `---`, with any `kind: List` document expanded into
+// its constituent items. Each returned object is stamped with a
+// last-applied-configuration annotation, mirroring what `kubectl apply`
+// does, so resourceManifestRead/resourceKustomizationRead can read it back
+// out for drift detection.
+func decodeManifests(content string) ([]*unstructured.Unstructured, error) {
+	var objs []*unstructured.Unstructured
+	for _, doc := range splitYAMLDocuments(content) {
+		m, err := yamlDocToMap(doc)
+		if err != nil {
+			return nil, fmt.Errorf("decoding document: %v", err)
+		}
+		if len(m) == 0 {
+			// A `---`-delimited chunk containing nothing but comments (e.g.
+			// the "# Source: chart/templates/x.yaml" headers helm template
+			// and kustomize build emit above every document) decodes to an
+			// empty map; skip it rather than treating it as a phantom object
+			// with no kind.
+			continue
+		}
+
+		if kind, _ := m["kind"].(string); kind == "List" {
+			items, _ := m["items"].([]interface{})
+			for _, raw := range items {
+				item, ok := raw.(map[string]interface{})
+				if !ok {
+					return nil, fmt.Errorf("List item was not an object: %v", raw)
+				}
+				objs = append(objs, &unstructured.Unstructured{Object: item})
+			}
+			continue
+		}
+		objs = append(objs, &unstructured.Unstructured{Object: m})
+	}
+	if len(objs) == 0 {
+		return nil, fmt.Errorf("content contained no kubernetes objects")
+	}
+
+	for _, obj := range objs {
+		// Discard any provenance annotations already present in the user's
+		// content (e.g. pasted back in from a previous `terraform show`)
+		// before snapshotting it, so they never end up baked into the
+		// last-applied-configuration annotation the provider diffs against.
+		stripProvenanceAnnotations(obj)
+		if err := annotateLastApplied(obj); err != nil {
+			return nil, err
+		}
+	}
+	return objs, nil
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDecodeManifestsMultiDocument(t *testing.T) {
+	content := strings.Join([]string{
+		"apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: a",
+		"apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: b",
+	}, "\n---\n")
+
+	objs, err := decodeManifests(content)
+	if err != nil {
+		t.Fatalf("decodeManifests: %v", err)
+	}
+	if len(objs) != 2 {
+		t.Fatalf("got %d objects, want 2", len(objs))
+	}
+	if objs[0].GetName() != "a" || objs[1].GetName() != "b" {
+		t.Fatalf("got names %q, %q, want a, b", objs[0].GetName(), objs[1].GetName())
+	}
+}
+
+func TestDecodeManifestsUnwrapsList(t *testing.T) {
+	content := `
+apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: a
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: b
+`
+	objs, err := decodeManifests(content)
+	if err != nil {
+		t.Fatalf("decodeManifests: %v", err)
+	}
+	if len(objs) != 2 {
+		t.Fatalf("got %d objects from a List, want 2", len(objs))
+	}
+	if objs[0].GetKind() != "ConfigMap" || objs[0].GetName() != "a" {
+		t.Fatalf("first list item = %s/%s, want ConfigMap/a", objs[0].GetKind(), objs[0].GetName())
+	}
+}
+
+// TestDecodeManifestsSkipsCommentOnlyDocuments guards the Helm output this
+// request exists to support: `helm template` emits a standalone
+// "---\n# Source: chart/templates/x.yaml" document, with no YAML object at
+// all, for every template that renders empty. That must not turn into a
+// phantom object with no kind.
+func TestDecodeManifestsSkipsCommentOnlyDocuments(t *testing.T) {
+	content := `
+---
+# Source: chart/templates/empty.yaml
+---
+# Source: chart/templates/a.yaml
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: a
+`
+	objs, err := decodeManifests(content)
+	if err != nil {
+		t.Fatalf("decodeManifests: %v", err)
+	}
+	if len(objs) != 1 {
+		t.Fatalf("got %d objects, want 1 (the comment-only document should be skipped)", len(objs))
+	}
+	if objs[0].GetName() != "a" {
+		t.Fatalf("got name %q, want %q", objs[0].GetName(), "a")
+	}
+}
+
+func TestDecodeManifestsNoDocuments(t *testing.T) {
+	if _, err := decodeManifests("   \n\n"); err == nil {
+		t.Fatalf("expected an error decoding content with no documents")
+	}
+}
+
+// TestDecodeManifestsStripsProvenanceBeforeSnapshotting guards the ordering
+// request #6's drift detection depends on: a provenance annotation already
+// present in the user's content (e.g. pasted back in from `terraform show`)
+// must not leak into the last-applied-configuration snapshot, or every
+// subsequent plan would see it as permanent drift.
+func TestDecodeManifestsStripsProvenanceBeforeSnapshotting(t *testing.T) {
+	content := `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: a
+  annotations:
+    terraform.io/change-cause: stale-value
+`
+	objs, err := decodeManifests(content)
+	if err != nil {
+		t.Fatalf("decodeManifests: %v", err)
+	}
+	snapshot := objs[0].GetAnnotations()[lastAppliedConfigAnnotation]
+	if strings.Contains(snapshot, changeCauseAnnotation) {
+		t.Fatalf("last-applied-configuration snapshot retained a provenance annotation: %s", snapshot)
+	}
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8sschema "k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+const lastAppliedConfigAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
+// objectID is one tracked object's identity as stored in terraform state. It
+// carries just enough to re-resolve the object's GroupVersionResource via
+// the RESTMapper and address it with the dynamic client, without depending
+// on the selflinks kubectl used to print (those are gone as of Kubernetes
+// 1.20). A resource's ID is the JSON-encoded list of these, since the
+// rendered content of a k8s_manifest or k8s_kustomization may contain more
+// than one document.
+type objectID struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name"`
+}
+
+func (id objectID) groupVersionKind() (k8sschema.GroupVersionKind, error) {
+	gv, err := k8sschema.ParseGroupVersion(id.APIVersion)
+	if err != nil {
+		return k8sschema.GroupVersionKind{}, fmt.Errorf("parsing apiVersion %q: %v", id.APIVersion, err)
+	}
+	return gv.WithKind(id.Kind), nil
+}
+
+// key identifies the object across plans, independent of where in the
+// manifest it's declared.
+func (id objectID) key() string {
+	return id.APIVersion + "/" + id.Kind + "/" + id.Namespace + "/" + id.Name
+}
+
+func (id objectID) String() string {
+	return fmt.Sprintf("%s/%s %s/%s", id.APIVersion, id.Kind, id.Namespace, id.Name)
+}
+
+// idForObject builds obj's tracked identity, defaulting its namespace the
+// same way resourceInterface does when obj omits one. Without this, a
+// namespace-scoped object declared without an explicit `namespace:` (the
+// normal style for Helm/kustomize output) would record a different key once
+// it comes back from the apiserver with its namespace populated, and every
+// later reconcileObjects would see it as newly added and fail with
+// AlreadyExists.
+func idForObject(mapping *meta.RESTMapping, obj *unstructured.Unstructured) objectID {
+	gvk := obj.GroupVersionKind()
+	return objectID{
+		APIVersion: gvk.GroupVersion().String(),
+		Kind:       gvk.Kind,
+		Namespace:  effectiveNamespace(mapping, obj.GetNamespace()),
+		Name:       obj.GetName(),
+	}
+}
+
+func encodeIDs(ids []objectID) (string, error) {
+	b, err := json.Marshal(ids)
+	if err != nil {
+		return "", fmt.Errorf("encoding resource id: %v", err)
+	}
+	return string(b), nil
+}
+
+func decodeIDs(s string) ([]objectID, error) {
+	var ids []objectID
+	if err := json.Unmarshal([]byte(s), &ids); err != nil {
+		return nil, fmt.Errorf("invalid resource id %q: %v", s, err)
+	}
+	return ids, nil
+}
+
+// setManifestIDs records the set of objects a k8s_manifest/k8s_kustomization
+// resource currently tracks. It's called even on a mid-loop failure in
+// createObjects/reconcileObjects so a partially applied manifest isn't
+// orphaned: Terraform will see the objects that did get created/patched and
+// can clean them up on the next apply or destroy.
+func setManifestIDs(d *schema.ResourceData, ids []objectID) error {
+	if len(ids) == 0 {
+		d.SetId("")
+		return nil
+	}
+	encoded, err := encodeIDs(ids)
+	if err != nil {
+		return err
+	}
+	d.SetId(encoded)
+	return nil
+}
+
+func annotateLastApplied(obj *unstructured.Unstructured) error {
+	b, err := json.Marshal(obj.Object)
+	if err != nil {
+		return fmt.Errorf("marshaling %s %q: %v", obj.GetKind(), obj.GetName(), err)
+	}
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[lastAppliedConfigAnnotation] = string(b)
+	obj.SetAnnotations(annotations)
+	return nil
+}
+
+// effectiveNamespace defaults namespace to "default" for namespace-scoped
+// GVKs, mirroring what the apiserver does for an object applied without an
+// explicit namespace, and returns "" for cluster-scoped ones.
+func effectiveNamespace(mapping *meta.RESTMapping, namespace string) string {
+	if mapping.Scope.Name() != meta.RESTScopeNameNamespace {
+		return ""
+	}
+	if namespace == "" {
+		return "default"
+	}
+	return namespace
+}
+
+// resourceInterface resolves the dynamic.ResourceInterface to use for obj,
+// namespacing it when the RESTMapper says the GVK is namespace-scoped.
+func resourceInterface(cfg *providerConfig, mapping *meta.RESTMapping, namespace string) dynamic.ResourceInterface {
+	namespace = effectiveNamespace(mapping, namespace)
+	if namespace == "" {
+		return cfg.dynamicClient.Resource(mapping.Resource)
+	}
+	return cfg.dynamicClient.Resource(mapping.Resource).Namespace(namespace)
+}
+
+func restMappingFor(cfg *providerConfig, gvk k8sschema.GroupVersionKind) (*meta.RESTMapping, error) {
+	mapping, err := cfg.restMapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return nil, fmt.Errorf("resolving REST mapping for %s: %v", gvk, err)
+	}
+	return mapping, nil
+}
+
+func deleteTrackedObject(cfg *providerConfig, id objectID) error {
+	gvk, err := id.groupVersionKind()
+	if err != nil {
+		return err
+	}
+	mapping, err := restMappingFor(cfg, gvk)
+	if err != nil {
+		return err
+	}
+	err = resourceInterface(cfg, mapping, id.Namespace).Delete(context.TODO(), id.Name, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("deleting %s: %v", id, err)
+	}
+	return nil
+}
+
+// createObjects creates every object in objs, stopping at the first error.
+// It always returns the IDs of the objects that were successfully created,
+// even alongside an error, so the caller can record partial progress.
+func createObjects(cfg *providerConfig, objs []*unstructured.Unstructured) ([]objectID, error) {
+	var ids []objectID
+	for _, obj := range objs {
+		gvk := obj.GroupVersionKind()
+		mapping, err := restMappingFor(cfg, gvk)
+		if err != nil {
+			return ids, err
+		}
+
+		id := idForObject(mapping, obj)
+
+		created, err := resourceInterface(cfg, mapping, id.Namespace).Create(context.TODO(), obj, metav1.CreateOptions{})
+		if err != nil {
+			return ids, fmt.Errorf("creating %s: %v", id, err)
+		}
+		ids = append(ids, idForObject(mapping, created))
+	}
+	return ids, nil
+}
+
+// reconcileObjects reconciles a previously tracked object set with a desired
+// one: objects present in both are patched, objects newly added to the
+// rendered content are created, and objects that disappeared from it since
+// the last apply are deleted. It always returns the IDs that should be
+// tracked going forward, even alongside an error.
+func reconcileObjects(cfg *providerConfig, existingIDs []objectID, desiredObjs []*unstructured.Unstructured, strategy string) ([]objectID, error) {
+	existingByKey := make(map[string]objectID, len(existingIDs))
+	for _, id := range existingIDs {
+		existingByKey[id.key()] = id
+	}
+
+	var newIDs []objectID
+	desiredKeys := make(map[string]bool, len(desiredObjs))
+	for _, obj := range desiredObjs {
+		gvk := obj.GroupVersionKind()
+		mapping, err := restMappingFor(cfg, gvk)
+		if err != nil {
+			return newIDs, err
+		}
+		id := idForObject(mapping, obj)
+		desiredKeys[id.key()] = true
+		newIDs = append(newIDs, id)
+
+		client := resourceInterface(cfg, mapping, id.Namespace)
+
+		if _, tracked := existingByKey[id.key()]; tracked {
+			current, err := client.Get(context.TODO(), id.Name, metav1.GetOptions{})
+			if err != nil {
+				return newIDs, fmt.Errorf("reading %s before update: %v", id, err)
+			}
+			if err := applyPatch(client, strategy, gvk, id.Name, current, obj); err != nil {
+				return newIDs, fmt.Errorf("updating %s: %v", id, err)
+			}
+			continue
+		}
+
+		// Newly added since the last apply.
+		if _, err := client.Create(context.TODO(), obj, metav1.CreateOptions{}); err != nil {
+			return newIDs, fmt.Errorf("creating %s: %v", id, err)
+		}
+	}
+
+	for key, id := range existingByKey {
+		if desiredKeys[key] {
+			continue
+		}
+		if err := deleteTrackedObject(cfg, id); err != nil {
+			return newIDs, fmt.Errorf("removing %s dropped from rendered content: %v", id, err)
+		}
+	}
+
+	return newIDs, nil
+}
+
+func deleteObjects(cfg *providerConfig, ids []objectID) error {
+	var errs []string
+	for _, id := range ids {
+		if err := deleteTrackedObject(cfg, id); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%s", strings.Join(errs, "; "))
+}
+
+// readObjects fetches the live state of every tracked object, skipping
+// (and therefore dropping from the returned ID list) any that no longer
+// exist. It returns each object's last-applied-configuration annotation
+// content alongside its ID, in the same order.
+func readObjects(cfg *providerConfig, ids []objectID) (liveIDs []objectID, manifests []string, err error) {
+	for _, id := range ids {
+		gvk, err := id.groupVersionKind()
+		if err != nil {
+			return nil, nil, err
+		}
+		mapping, err := restMappingFor(cfg, gvk)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		obj, err := resourceInterface(cfg, mapping, id.Namespace).Get(context.TODO(), id.Name, metav1.GetOptions{})
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return nil, nil, fmt.Errorf("reading %s: %v", id, err)
+		}
+
+		rawConfiguration, ok := obj.GetAnnotations()[lastAppliedConfigAnnotation]
+		if !ok {
+			return nil, nil, fmt.Errorf("%s has no %s annotation; it was not created by this provider", id, lastAppliedConfigAnnotation)
+		}
+		liveIDs = append(liveIDs, idForObject(mapping, obj))
+		manifests = append(manifests, rawConfiguration)
+	}
+	return liveIDs, manifests, nil
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8sschema "k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func TestObjectIDKey(t *testing.T) {
+	a := objectID{APIVersion: "v1", Kind: "ConfigMap", Namespace: "default", Name: "a"}
+	b := objectID{APIVersion: "v1", Kind: "ConfigMap", Namespace: "default", Name: "b"}
+	if a.key() == b.key() {
+		t.Fatalf("distinct objects produced the same key: %q", a.key())
+	}
+	if a.key() != (objectID{APIVersion: "v1", Kind: "ConfigMap", Namespace: "default", Name: "a"}).key() {
+		t.Fatalf("identical objects produced different keys")
+	}
+}
+
+func TestObjectIDGroupVersionKind(t *testing.T) {
+	cases := []struct {
+		name    string
+		id      objectID
+		want    k8sschema.GroupVersionKind
+		wantErr bool
+	}{
+		{
+			name: "core group",
+			id:   objectID{APIVersion: "v1", Kind: "Pod"},
+			want: k8sschema.GroupVersionKind{Version: "v1", Kind: "Pod"},
+		},
+		{
+			name: "named group",
+			id:   objectID{APIVersion: "apps/v1", Kind: "Deployment"},
+			want: k8sschema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"},
+		},
+		{
+			name:    "malformed apiVersion",
+			id:      objectID{APIVersion: "apps/v1/extra", Kind: "Deployment"},
+			wantErr: true,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := tc.id.groupVersionKind()
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tc.want {
+				t.Fatalf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+// newTestProviderConfig builds a providerConfig backed by a fake dynamic
+// client and a static RESTMapper that both know about a single namespaced
+// "widgets" resource, for exercising reconcileObjects/deleteObjects without a
+// live cluster.
+func newTestProviderConfig(objs ...runtime.Object) *providerConfig {
+	gvk := k8sschema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Widget"}
+	gvr := k8sschema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "widgets"}
+
+	mapper := meta.NewDefaultRESTMapper([]k8sschema.GroupVersion{gvk.GroupVersion()})
+	mapper.Add(gvk, meta.RESTScopeNamespace)
+
+	scheme := runtime.NewScheme()
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(scheme, map[k8sschema.GroupVersionResource]string{
+		gvr: "WidgetList",
+	}, objs...)
+
+	return &providerConfig{
+		dynamicClient: client,
+		restMapper:    mapper,
+	}
+}
+
+func widget(namespace, name, value string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "example.com/v1",
+		"kind":       "Widget",
+		"metadata": map[string]interface{}{
+			"namespace": namespace,
+			"name":      name,
+		},
+		"spec": map[string]interface{}{
+			"value": value,
+		},
+	}}
+}
+
+// widgetMapping resolves the RESTMapping newTestProviderConfig registers for
+// Widget, for tests that need to build an objectID the same way production
+// code does.
+func widgetMapping(t *testing.T, cfg *providerConfig) *meta.RESTMapping {
+	t.Helper()
+	mapping, err := restMappingFor(cfg, k8sschema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Widget"})
+	if err != nil {
+		t.Fatalf("resolving widget mapping: %v", err)
+	}
+	return mapping
+}
+
+// TestIdForObjectDefaultsNamespace guards against the bug where an object
+// declared without an explicit `namespace:` (the normal style for
+// Helm/kustomize output) gets a different tracked key than the same object
+// once it comes back from the apiserver with its namespace populated.
+func TestIdForObjectDefaultsNamespace(t *testing.T) {
+	cfg := newTestProviderConfig()
+	mapping := widgetMapping(t, cfg)
+
+	withoutNamespace := idForObject(mapping, widget("", "a", "v"))
+	withDefaultNamespace := idForObject(mapping, widget("default", "a", "v"))
+	if withoutNamespace.key() != withDefaultNamespace.key() {
+		t.Fatalf("idForObject(namespace=%q).key() = %q, want it to match namespace=%q's key %q",
+			"", withoutNamespace.key(), "default", withDefaultNamespace.key())
+	}
+}
+
+// TestReconcileObjectsDiffing exercises the three cases reconcileObjects has
+// to juggle in a single pass: an object that's still declared gets patched
+// in place, one newly added to the rendered content gets created, and one
+// dropped since the last apply gets deleted.
+func TestReconcileObjectsDiffing(t *testing.T) {
+	kept := widget("default", "kept", "old")
+	dropped := widget("default", "dropped", "old")
+	cfg := newTestProviderConfig(kept, dropped)
+	mapping := widgetMapping(t, cfg)
+
+	existingIDs := []objectID{idForObject(mapping, kept), idForObject(mapping, dropped)}
+	desired := []*unstructured.Unstructured{
+		widget("default", "kept", "new"),
+		widget("default", "added", "new"),
+	}
+
+	gotIDs, err := reconcileObjects(cfg, existingIDs, desired, "merge")
+	if err != nil {
+		t.Fatalf("reconcileObjects: %v", err)
+	}
+
+	var gotKeys []string
+	for _, id := range gotIDs {
+		gotKeys = append(gotKeys, id.key())
+	}
+	sort.Strings(gotKeys)
+	wantKeys := []string{
+		idForObject(mapping, widget("default", "added", "")).key(),
+		idForObject(mapping, widget("default", "kept", "")).key(),
+	}
+	sort.Strings(wantKeys)
+	if len(gotKeys) != len(wantKeys) || gotKeys[0] != wantKeys[0] || gotKeys[1] != wantKeys[1] {
+		t.Fatalf("got IDs %v, want %v", gotKeys, wantKeys)
+	}
+
+	gvr := k8sschema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "widgets"}
+
+	patched, err := cfg.dynamicClient.Resource(gvr).Namespace("default").Get(context.TODO(), "kept", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("getting patched object: %v", err)
+	}
+	value, _, _ := unstructured.NestedString(patched.Object, "spec", "value")
+	if value != "new" {
+		t.Fatalf("kept widget's spec.value = %q, want %q", value, "new")
+	}
+
+	if _, err := cfg.dynamicClient.Resource(gvr).Namespace("default").Get(context.TODO(), "added", metav1.GetOptions{}); err != nil {
+		t.Fatalf("newly added widget was not created: %v", err)
+	}
+
+	if _, err := cfg.dynamicClient.Resource(gvr).Namespace("default").Get(context.TODO(), "dropped", metav1.GetOptions{}); err == nil {
+		t.Fatalf("widget dropped from rendered content was not deleted")
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	"github.com/wI2L/jsondiff"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8sschema "k8s.io/apimachinery/pkg/runtime/schema"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+const fieldManager = "terraform-provider-k8s"
+
+// applyPatch updates name on the cluster according to strategy, diffing
+// current against modified to compute the patch body. "strategic" is the
+// default and automatically falls back to a JSON merge patch for objects
+// strategic merge can't handle, such as CRD-backed resources whose schema
+// carries no strategic-merge metadata.
+func applyPatch(client dynamic.ResourceInterface, strategy string, gvk k8sschema.GroupVersionKind, name string, current, modified *unstructured.Unstructured) error {
+	switch strategy {
+	case "merge":
+		return applyMergePatch(client, name, current, modified)
+	case "json":
+		return applyJSONPatch(client, name, current, modified)
+	case "server-side-apply":
+		return applyServerSideApply(client, name, modified)
+	default:
+		return applyStrategicPatch(client, gvk, name, current, modified)
+	}
+}
+
+// applyStrategicPatch tries a strategic merge patch first, and transparently
+// retries with a JSON merge patch when the GVK has no strategic-merge
+// metadata registered, which is the case for CRDs and any other type outside
+// client-go's built-in scheme. This is the same workaround Pulumi's
+// kubernetes provider adopted for CRDs.
+func applyStrategicPatch(client dynamic.ResourceInterface, gvk k8sschema.GroupVersionKind, name string, current, modified *unstructured.Unstructured) error {
+	currentJSON, err := json.Marshal(current.Object)
+	if err != nil {
+		return fmt.Errorf("marshaling current object: %v", err)
+	}
+	modifiedJSON, err := json.Marshal(modified.Object)
+	if err != nil {
+		return fmt.Errorf("marshaling desired object: %v", err)
+	}
+
+	// CreateTwoWayMergePatch needs the typed Go struct client-go's scheme
+	// registers for gvk to look up each field's patchStrategy/patchMergeKey
+	// tags (e.g. merging a Pod's containers or volumes by name); passing it
+	// an unstructured.Unstructured has no such tags and silently turns every
+	// field into a wholesale replace. CRDs and other types outside the
+	// built-in scheme have no such struct at all, so fall straight through
+	// to a JSON merge patch for them rather than attempting a patch that can
+	// never carry any merge-by-name metadata.
+	dataStruct, err := scheme.Scheme.New(gvk)
+	if err != nil {
+		return applyMergePatch(client, name, current, modified)
+	}
+
+	patch, err := strategicpatch.CreateTwoWayMergePatch(currentJSON, modifiedJSON, dataStruct)
+	if err != nil {
+		if isUnsupportedStrategicMergeError(err) {
+			return applyMergePatch(client, name, current, modified)
+		}
+		return fmt.Errorf("computing strategic merge patch: %v", err)
+	}
+
+	_, err = client.Patch(context.TODO(), name, k8stypes.StrategicMergePatchType, patch, metav1.PatchOptions{})
+	if err == nil {
+		return nil
+	}
+	if !isUnsupportedStrategicMergeError(err) {
+		return err
+	}
+	return applyMergePatch(client, name, current, modified)
+}
+
+// isUnsupportedStrategicMergeError recognizes the apiserver's error messages
+// when it has no strategic-merge patch metadata for a GVK, which happens for
+// CRDs and other types outside the built-in API machinery's scheme.
+func isUnsupportedStrategicMergeError(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, "unable to find api field") ||
+		strings.Contains(msg, "expected kind, but got map") ||
+		strings.Contains(msg, "is invalid: patch: Invalid value")
+}
+
+func applyMergePatch(client dynamic.ResourceInterface, name string, current, modified *unstructured.Unstructured) error {
+	currentJSON, err := json.Marshal(current.Object)
+	if err != nil {
+		return fmt.Errorf("marshaling current object: %v", err)
+	}
+	modifiedJSON, err := json.Marshal(modified.Object)
+	if err != nil {
+		return fmt.Errorf("marshaling desired object: %v", err)
+	}
+
+	patch, err := jsonpatch.CreateMergePatch(currentJSON, modifiedJSON)
+	if err != nil {
+		return fmt.Errorf("computing JSON merge patch: %v", err)
+	}
+
+	_, err = client.Patch(context.TODO(), name, k8stypes.MergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
+
+func applyJSONPatch(client dynamic.ResourceInterface, name string, current, modified *unstructured.Unstructured) error {
+	currentJSON, err := json.Marshal(current.Object)
+	if err != nil {
+		return fmt.Errorf("marshaling current object: %v", err)
+	}
+	modifiedJSON, err := json.Marshal(modified.Object)
+	if err != nil {
+		return fmt.Errorf("marshaling desired object: %v", err)
+	}
+
+	patch, err := jsondiff.CompareJSON(currentJSON, modifiedJSON)
+	if err != nil {
+		return fmt.Errorf("computing JSON patch: %v", err)
+	}
+	patchBytes, err := json.Marshal(patch)
+	if err != nil {
+		return fmt.Errorf("encoding JSON patch: %v", err)
+	}
+
+	_, err = client.Patch(context.TODO(), name, k8stypes.JSONPatchType, patchBytes, metav1.PatchOptions{})
+	return err
+}
+
+func applyServerSideApply(client dynamic.ResourceInterface, name string, modified *unstructured.Unstructured) error {
+	modifiedJSON, err := json.Marshal(modified.Object)
+	if err != nil {
+		return fmt.Errorf("marshaling desired object: %v", err)
+	}
+
+	force := true
+	_, err = client.Patch(context.TODO(), name, k8stypes.ApplyPatchType, modifiedJSON, metav1.PatchOptions{
+		FieldManager: fieldManager,
+		Force:        &force,
+	})
+	return err
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestIsUnsupportedStrategicMergeError(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil has no message to match", errors.New(""), false},
+		{"unable to find api field", errors.New(`unable to find api field in struct Unstructured for the json field "spec"`), true},
+		{"expected kind got map", errors.New("expected kind, but got map"), true},
+		{"invalid patch value", errors.New("CustomResourceDefinition.apiextensions.k8s.io \"x\" is invalid: patch: Invalid value"), true},
+		{"unrelated apiserver error", errors.New(`widgets.example.com "x" not found`), false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := isUnsupportedStrategicMergeError(tc.err); got != tc.want {
+				t.Fatalf("isUnsupportedStrategicMergeError(%q) = %v, want %v", tc.err, got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Provenance annotations stamped onto applied objects so Terraform runs can
+// be correlated with rollouts, mirroring what `kubectl apply --record` did
+// for `kubernetes.io/change-cause`.
+const (
+	changeCauseAnnotation     = "terraform.io/change-cause"
+	workspaceAnnotation       = "terraform.io/workspace"
+	resourceAddressAnnotation = "terraform.io/resource-address"
+)
+
+var provenanceAnnotationKeys = []string{
+	changeCauseAnnotation,
+	workspaceAnnotation,
+	resourceAddressAnnotation,
+}
+
+// stripProvenanceAnnotations removes any of the annotations this provider
+// injects from obj. It's used before snapshotting the last-applied
+// configuration so a value that changes from run to run (like change-cause)
+// never shows up as drift.
+func stripProvenanceAnnotations(obj *unstructured.Unstructured) {
+	annotations := obj.GetAnnotations()
+	if len(annotations) == 0 {
+		return
+	}
+	changed := false
+	for _, key := range provenanceAnnotationKeys {
+		if _, ok := annotations[key]; ok {
+			delete(annotations, key)
+			changed = true
+		}
+	}
+	if changed {
+		obj.SetAnnotations(annotations)
+	}
+}
+
+// injectProvenanceAnnotations stamps obj with the provider's change-cause
+// and workspace, plus resourceAddress when the resource sets one. It must
+// run after decodeManifests has already snapshotted the last-applied
+// configuration, so these annotations are never part of that snapshot.
+func injectProvenanceAnnotations(obj *unstructured.Unstructured, cfg *providerConfig, resourceAddress string) {
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	if cfg.changeCause != "" {
+		annotations[changeCauseAnnotation] = cfg.changeCause
+	}
+	if cfg.workspace != "" {
+		annotations[workspaceAnnotation] = cfg.workspace
+	}
+	if resourceAddress != "" {
+		annotations[resourceAddressAnnotation] = resourceAddress
+	}
+	obj.SetAnnotations(annotations)
+}
+
+func injectProvenanceAnnotationsAll(objs []*unstructured.Unstructured, cfg *providerConfig, resourceAddress string) {
+	for _, obj := range objs {
+		injectProvenanceAnnotations(obj, cfg, resourceAddress)
+	}
+}
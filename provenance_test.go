@@ -0,0 +1,64 @@
+package main
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestStripProvenanceAnnotations(t *testing.T) {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	obj.SetAnnotations(map[string]string{
+		changeCauseAnnotation:     "old-cause",
+		workspaceAnnotation:       "old-workspace",
+		resourceAddressAnnotation: "old-address",
+		"keep.me/around":          "yes",
+	})
+
+	stripProvenanceAnnotations(obj)
+
+	annotations := obj.GetAnnotations()
+	for _, key := range provenanceAnnotationKeys {
+		if _, ok := annotations[key]; ok {
+			t.Fatalf("provenance annotation %q was not stripped", key)
+		}
+	}
+	if annotations["keep.me/around"] != "yes" {
+		t.Fatalf("stripProvenanceAnnotations removed an unrelated annotation")
+	}
+}
+
+// TestInjectProvenanceAfterStripRoundTrip mirrors the
+// decodeManifests -> injectProvenanceAnnotationsAll pipeline:
+// stripProvenanceAnnotations runs first so the last-applied snapshot never
+// sees this provider's own annotations, then injectProvenanceAnnotations
+// stamps the current run's values back on afterwards.
+func TestInjectProvenanceAfterStripRoundTrip(t *testing.T) {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	obj.SetAnnotations(map[string]string{changeCauseAnnotation: "stale"})
+
+	stripProvenanceAnnotations(obj)
+	cfg := &providerConfig{changeCause: "fresh", workspace: "default"}
+	injectProvenanceAnnotations(obj, cfg, "k8s_manifest.example")
+
+	annotations := obj.GetAnnotations()
+	if annotations[changeCauseAnnotation] != "fresh" {
+		t.Fatalf("change-cause = %q, want %q", annotations[changeCauseAnnotation], "fresh")
+	}
+	if annotations[workspaceAnnotation] != "default" {
+		t.Fatalf("workspace = %q, want %q", annotations[workspaceAnnotation], "default")
+	}
+	if annotations[resourceAddressAnnotation] != "k8s_manifest.example" {
+		t.Fatalf("resource-address = %q, want %q", annotations[resourceAddressAnnotation], "k8s_manifest.example")
+	}
+}
+
+func TestInjectProvenanceAnnotationsOmitsEmptyResourceAddress(t *testing.T) {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	cfg := &providerConfig{}
+	injectProvenanceAnnotations(obj, cfg, "")
+
+	if _, ok := obj.GetAnnotations()[resourceAddressAnnotation]; ok {
+		t.Fatalf("resource-address annotation set despite an empty resourceAddress")
+	}
+}
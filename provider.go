@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/terraform"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// Provider returns the terraform-provider-k8s schema.Provider. Resource CRUD
+// functions reach the cluster exclusively through the providerConfig built in
+// providerConfigure and handed to them as the untyped `m` argument, rather
+// than shelling out to the kubectl binary.
+func Provider() terraform.ResourceProvider {
+	return &schema.Provider{
+		Schema: map[string]*schema.Schema{
+			"kubeconfig": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("KUBECONFIG", ""),
+				Description: "Path to a kubeconfig file. Defaults to the KUBECONFIG environment variable, the default kubeconfig loading rules, or in-cluster config when none of those are set.",
+			},
+			"context": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Context to use from the kubeconfig file. Defaults to the kubeconfig's current context.",
+			},
+			"host": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "The hostname (in form of URI) of the Kubernetes API server. Overrides kubeconfig when set.",
+			},
+			"token": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Sensitive:   true,
+				Description: "Bearer token used to authenticate to the Kubernetes API server when `host` is set.",
+			},
+			"cluster_ca_certificate": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "PEM-encoded CA certificate used to verify the Kubernetes API server's certificate when `host` is set.",
+			},
+			"change_cause": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("TF_CHANGE_CAUSE", ""),
+				Description: "Stamped onto applied objects as the `terraform.io/change-cause` annotation, e.g. a CI run URL or commit SHA. Defaults to the TF_CHANGE_CAUSE environment variable.",
+			},
+			"workspace": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("TF_WORKSPACE", ""),
+				Description: "Stamped onto applied objects as the `terraform.io/workspace` annotation. Defaults to the TF_WORKSPACE environment variable.",
+			},
+		},
+		ResourcesMap: map[string]*schema.Resource{
+			"k8s_manifest":      resourceManifest(),
+			"k8s_kustomization": resourceKustomization(),
+		},
+		ConfigureFunc: providerConfigure,
+	}
+}
+
+// providerConfig bundles the pieces resource CRUD functions need to talk to
+// the cluster: a dynamic client able to operate on any GVK, and a RESTMapper
+// to resolve a decoded object's GroupVersionKind to the GroupVersionResource
+// and scope (namespaced vs. cluster-scoped) the dynamic client needs.
+type providerConfig struct {
+	dynamicClient dynamic.Interface
+	restMapper    meta.RESTMapper
+	changeCause   string
+	workspace     string
+}
+
+func providerConfigure(d *schema.ResourceData) (interface{}, error) {
+	restConfig, err := buildRestConfig(d)
+	if err != nil {
+		return nil, fmt.Errorf("building kubernetes client config: %v", err)
+	}
+
+	dynamicClient, err := dynamic.NewForConfig(restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("creating dynamic client: %v", err)
+	}
+
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("creating discovery client: %v", err)
+	}
+	restMapper := restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(discoveryClient))
+
+	return &providerConfig{
+		dynamicClient: dynamicClient,
+		restMapper:    restMapper,
+		changeCause:   d.Get("change_cause").(string),
+		workspace:     d.Get("workspace").(string),
+	}, nil
+}
+
+// buildRestConfig resolves the provider's connection settings in order of
+// precedence: explicit host/token, then an explicit kubeconfig path/context,
+// then in-cluster config, then the default kubeconfig loading rules.
+func buildRestConfig(d *schema.ResourceData) (*rest.Config, error) {
+	if host := d.Get("host").(string); host != "" {
+		cfg := &rest.Config{
+			Host:        host,
+			BearerToken: d.Get("token").(string),
+		}
+		if ca := d.Get("cluster_ca_certificate").(string); ca != "" {
+			cfg.TLSClientConfig.CAData = []byte(ca)
+		}
+		return cfg, nil
+	}
+
+	overrides := &clientcmd.ConfigOverrides{}
+	if kctx := d.Get("context").(string); kctx != "" {
+		overrides.CurrentContext = kctx
+	}
+
+	if path := d.Get("kubeconfig").(string); path != "" {
+		loadingRules := &clientcmd.ClientConfigLoadingRules{ExplicitPath: path}
+		return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, overrides).ClientConfig()
+	}
+
+	if cfg, err := rest.InClusterConfig(); err == nil {
+		return cfg, nil
+	}
+
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, overrides).ClientConfig()
+}
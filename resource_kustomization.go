@@ -0,0 +1,198 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/helper/validation"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+)
+
+// resourceKustomization renders a kustomize overlay into a set of Kubernetes
+// objects and applies them through the same create/read/update/delete path
+// k8s_manifest uses for multi-document content, so a kustomize base can be
+// managed directly without pre-rendering it with `data "external"`.
+func resourceKustomization() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceKustomizationCreate,
+		Read:   resourceKustomizationRead,
+		Update: resourceKustomizationUpdate,
+		Delete: resourceKustomizationDelete,
+		Schema: map[string]*schema.Schema{
+			"path": &schema.Schema{
+				Type:          schema.TypeString,
+				Optional:      true,
+				ConflictsWith: []string{"url"},
+				Description:   "Local path to the kustomization directory.",
+			},
+			"url": &schema.Schema{
+				Type:          schema.TypeString,
+				Optional:      true,
+				ConflictsWith: []string{"path"},
+				Description:   "Remote kustomization reference, e.g. `github.com/org/repo//overlay?ref=main`.",
+			},
+			"kustomize_options": &schema.Schema{
+				Type:     schema.TypeList,
+				Optional: true,
+				MaxItems: 1,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"load_restrictor": &schema.Schema{
+							Type:         schema.TypeString,
+							Optional:     true,
+							Default:      "rootOnly",
+							ValidateFunc: validation.StringInSlice([]string{"rootOnly", "none"}, false),
+							Description:  "Whether bases may be loaded from outside the kustomization root (`none`) or not (`rootOnly`, the default).",
+						},
+						"enable_helm": &schema.Schema{
+							Type:        schema.TypeBool,
+							Optional:    true,
+							Default:     false,
+							Description: "Allow the kustomization to invoke `helm` via `helmCharts`.",
+						},
+						"enable_alpha_plugins": &schema.Schema{
+							Type:        schema.TypeBool,
+							Optional:    true,
+							Default:     false,
+							Description: "Allow the kustomization to use alpha plugins and exec functions.",
+						},
+					},
+				},
+			},
+			"patch_strategy": &schema.Schema{
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "strategic",
+				ValidateFunc: validation.StringInSlice([]string{"strategic", "merge", "json", "server-side-apply"}, false),
+				Description:  "How updates are applied. See `k8s_manifest`'s attribute of the same name.",
+			},
+			"rendered_manifests": &schema.Schema{
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"resource_address": &schema.Schema{
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "See `k8s_manifest`'s attribute of the same name.",
+			},
+		},
+	}
+}
+
+// kustomizationTarget resolves the build target (`path` or `url`) and the
+// kustomizeOptions to render it with from resource config.
+func kustomizationTarget(d *schema.ResourceData) (string, kustomizeOptions, error) {
+	path := d.Get("path").(string)
+	url := d.Get("url").(string)
+	if path == "" && url == "" {
+		return "", kustomizeOptions{}, fmt.Errorf("one of `path` or `url` must be set")
+	}
+	target := path
+	if target == "" {
+		target = url
+	}
+
+	opts := kustomizeOptions{loadRestrictor: "rootOnly"}
+	if raw, ok := d.GetOk("kustomize_options"); ok {
+		if list := raw.([]interface{}); len(list) > 0 {
+			block := list[0].(map[string]interface{})
+			opts.loadRestrictor = block["load_restrictor"].(string)
+			opts.enableHelm = block["enable_helm"].(bool)
+			opts.enableAlphaPlugins = block["enable_alpha_plugins"].(bool)
+		}
+	}
+	return target, opts, nil
+}
+
+func resourceKustomizationCreate(d *schema.ResourceData, m interface{}) error {
+	cfg := m.(*providerConfig)
+
+	target, opts, err := kustomizationTarget(d)
+	if err != nil {
+		return err
+	}
+	rendered, err := renderKustomization(filesys.MakeFsOnDisk(), target, opts)
+	if err != nil {
+		return err
+	}
+	objs, err := decodeManifests(rendered)
+	if err != nil {
+		return err
+	}
+	injectProvenanceAnnotationsAll(objs, cfg, d.Get("resource_address").(string))
+
+	ids, createErr := createObjects(cfg, objs)
+	if setErr := setManifestIDs(d, ids); setErr != nil {
+		return setErr
+	}
+	if createErr != nil {
+		return createErr
+	}
+	return d.Set("rendered_manifests", splitYAMLDocuments(rendered))
+}
+
+func resourceKustomizationUpdate(d *schema.ResourceData, m interface{}) error {
+	cfg := m.(*providerConfig)
+
+	existingIDs, err := decodeIDs(d.Id())
+	if err != nil {
+		return err
+	}
+
+	target, opts, err := kustomizationTarget(d)
+	if err != nil {
+		return err
+	}
+	rendered, err := renderKustomization(filesys.MakeFsOnDisk(), target, opts)
+	if err != nil {
+		return err
+	}
+	desiredObjs, err := decodeManifests(rendered)
+	if err != nil {
+		return err
+	}
+	injectProvenanceAnnotationsAll(desiredObjs, cfg, d.Get("resource_address").(string))
+
+	newIDs, reconcileErr := reconcileObjects(cfg, existingIDs, desiredObjs, d.Get("patch_strategy").(string))
+	if setErr := setManifestIDs(d, newIDs); setErr != nil {
+		return setErr
+	}
+	if reconcileErr != nil {
+		return reconcileErr
+	}
+	return d.Set("rendered_manifests", splitYAMLDocuments(rendered))
+}
+
+func resourceKustomizationDelete(d *schema.ResourceData, m interface{}) error {
+	cfg := m.(*providerConfig)
+
+	ids, err := decodeIDs(d.Id())
+	if err != nil {
+		return err
+	}
+	return deleteObjects(cfg, ids)
+}
+
+func resourceKustomizationRead(d *schema.ResourceData, m interface{}) error {
+	cfg := m.(*providerConfig)
+
+	ids, err := decodeIDs(d.Id())
+	if err != nil {
+		return err
+	}
+
+	liveIDs, manifests, err := readObjects(cfg, ids)
+	if err != nil {
+		return err
+	}
+	if len(liveIDs) == 0 {
+		d.SetId("")
+		return nil
+	}
+
+	if err := d.Set("rendered_manifests", manifests); err != nil {
+		return err
+	}
+	return setManifestIDs(d, liveIDs)
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/helper/validation"
+)
+
+func resourceManifest() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceManifestCreate,
+		Read:   resourceManifestRead,
+		Update: resourceManifestUpdate,
+		Delete: resourceManifestDelete,
+		Schema: map[string]*schema.Schema{
+			"content": &schema.Schema{
+				Type:     schema.TypeString,
+				Required: true,
+				StateFunc: func(thing interface{}) string {
+					return attemptNormalizeManifestContent(thing.(string))
+				},
+			},
+			"patch_strategy": &schema.Schema{
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "strategic",
+				ValidateFunc: validation.StringInSlice([]string{"strategic", "merge", "json", "server-side-apply"}, false),
+				Description:  "How updates are applied: `strategic` (the default; falls back to `merge` automatically when the live object has no registered strategic-merge metadata, e.g. CRDs), `merge` (RFC 7396 JSON merge patch), `json` (RFC 6902 JSON patch), or `server-side-apply`.",
+			},
+			"wait_for": &schema.Schema{
+				Type:     schema.TypeList,
+				Optional: true,
+				MaxItems: 1,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"rollout": &schema.Schema{
+							Type:        schema.TypeBool,
+							Optional:    true,
+							Default:     false,
+							Description: "Wait for a Deployment/StatefulSet/DaemonSet rollout to finish, equivalent to `kubectl rollout status`.",
+						},
+						"condition": &schema.Schema{
+							Type:     schema.TypeList,
+							Optional: true,
+							Elem: &schema.Resource{
+								Schema: map[string]*schema.Schema{
+									"type":   &schema.Schema{Type: schema.TypeString, Required: true},
+									"status": &schema.Schema{Type: schema.TypeString, Required: true},
+								},
+							},
+							Description: "Wait until `.status.conditions` contains an entry with this `type` and `status`.",
+						},
+						"field": &schema.Schema{
+							Type:     schema.TypeList,
+							Optional: true,
+							Elem: &schema.Resource{
+								Schema: map[string]*schema.Schema{
+									"json_path": &schema.Schema{Type: schema.TypeString, Required: true},
+									"value":     &schema.Schema{Type: schema.TypeString, Optional: true},
+								},
+							},
+							Description: "Wait until `json_path` (in kubectl's `-o jsonpath=` syntax) evaluates to `value`.",
+						},
+						"timeout": &schema.Schema{
+							Type:        schema.TypeString,
+							Optional:    true,
+							Default:     "5m",
+							Description: "How long to wait before giving up, as a Go duration string.",
+						},
+					},
+				},
+			},
+			"resource_address": &schema.Schema{
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Stamped onto applied objects as the `terraform.io/resource-address` annotation, e.g. `k8s_manifest.this`. Terraform doesn't expose a resource's own address to its provider, so this must be set explicitly (e.g. from a local value built with `path.module` and the resource's own name) to be populated.",
+			},
+		},
+		Importer: &schema.ResourceImporter{
+			State: schema.ImportStatePassthrough,
+		},
+	}
+}
+
+func resourceManifestCreate(d *schema.ResourceData, m interface{}) error {
+	cfg := m.(*providerConfig)
+
+	objs, err := decodeManifests(d.Get("content").(string))
+	if err != nil {
+		return err
+	}
+	injectProvenanceAnnotationsAll(objs, cfg, d.Get("resource_address").(string))
+
+	ids, createErr := createObjects(cfg, objs)
+	if setErr := setManifestIDs(d, ids); setErr != nil {
+		return setErr
+	}
+	if createErr != nil {
+		return createErr
+	}
+
+	wf, err := parseWaitFor(d)
+	if err != nil {
+		return err
+	}
+	return waitForObjects(cfg, ids, wf)
+}
+
+func resourceManifestUpdate(d *schema.ResourceData, m interface{}) error {
+	cfg := m.(*providerConfig)
+
+	existingIDs, err := decodeIDs(d.Id())
+	if err != nil {
+		return err
+	}
+	desiredObjs, err := decodeManifests(d.Get("content").(string))
+	if err != nil {
+		return err
+	}
+	injectProvenanceAnnotationsAll(desiredObjs, cfg, d.Get("resource_address").(string))
+
+	newIDs, reconcileErr := reconcileObjects(cfg, existingIDs, desiredObjs, d.Get("patch_strategy").(string))
+	if setErr := setManifestIDs(d, newIDs); setErr != nil {
+		return setErr
+	}
+	if reconcileErr != nil {
+		return reconcileErr
+	}
+
+	wf, err := parseWaitFor(d)
+	if err != nil {
+		return err
+	}
+	return waitForObjects(cfg, newIDs, wf)
+}
+
+func resourceManifestDelete(d *schema.ResourceData, m interface{}) error {
+	cfg := m.(*providerConfig)
+
+	ids, err := decodeIDs(d.Id())
+	if err != nil {
+		return err
+	}
+	return deleteObjects(cfg, ids)
+}
+
+func resourceManifestRead(d *schema.ResourceData, m interface{}) error {
+	cfg := m.(*providerConfig)
+
+	ids, err := decodeIDs(d.Id())
+	if err != nil {
+		return err
+	}
+
+	liveIDs, manifests, err := readObjects(cfg, ids)
+	if err != nil {
+		return err
+	}
+	if len(liveIDs) == 0 {
+		d.SetId("")
+		return nil
+	}
+
+	d.Set("content", strings.Join(manifests, "\n---\n"))
+	return setManifestIDs(d, liveIDs)
+}
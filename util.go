@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/tdewolff/minify"
+	minjson "github.com/tdewolff/minify/json"
+)
+
+// normalizeInput converts everything to minified JSON.  This is convenient
+// because it makes the state smaller, which helps in case you are using the
+// consul backend to store your data.  It also helps because it avoids subtle
+// edge case bugs and formatting issues associated with slurping yaml in, then
+// just spitting it out.  I have seen it cause problems when multi-line
+// strings enter the picture.  Far better to normalize to json
+func normalizeInput(input string) (string, error) {
+	j, err := yaml.YAMLToJSON([]byte(input))
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	r := strings.NewReader(string(j))
+	m := minify.New()
+	m.AddFuncRegexp(regexp.MustCompile("[/+]json$"), minjson.Minify)
+	if err := m.Minify("application/json", &b, r); err != nil {
+		return "", err
+	}
+	return string(b.String()), nil
+}
+
+// yamlDocumentSeparator matches a line containing only `---`, the boundary
+// between documents in a multi-document YAML stream.
+var yamlDocumentSeparator = regexp.MustCompile(`(?m)^---\s*$`)
+
+// splitYAMLDocuments splits content on `---` document boundaries, discarding
+// any documents that are empty or only whitespace/comments.
+func splitYAMLDocuments(content string) []string {
+	var docs []string
+	for _, part := range yamlDocumentSeparator.Split(content, -1) {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		docs = append(docs, part)
+	}
+	return docs
+}
+
+// yamlDocToMap decodes a single YAML (or JSON) document into a generic map
+// suitable for building an unstructured.Unstructured.
+func yamlDocToMap(doc string) (map[string]interface{}, error) {
+	j, err := yaml.YAMLToJSON([]byte(doc))
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(j, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// normalizeManifestContent normalizes each `---`-separated document in
+// content independently and rejoins them, so multi-document manifests
+// continue to diff cleanly in terraform state.
+func normalizeManifestContent(content string) (string, error) {
+	docs := splitYAMLDocuments(content)
+	normalized := make([]string, 0, len(docs))
+	for _, doc := range docs {
+		n, err := normalizeInput(doc)
+		if err != nil {
+			return "", err
+		}
+		normalized = append(normalized, n)
+	}
+	return strings.Join(normalized, "\n---\n"), nil
+}
+
+func attemptNormalizeManifestContent(content string) string {
+	if normalized, err := normalizeManifestContent(content); err != nil {
+		return content
+	} else {
+		return normalized
+	}
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/hashicorp/terraform/helper/schema"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/jsonpath"
+)
+
+// waitBackoff polls each tracked object with exponentially increasing
+// intervals, starting at waitBackoffInitial and capped at waitBackoffCap, so
+// a slow rollout doesn't get hammered with a Get every couple of seconds for
+// the full length of a long wait_for.timeout.
+const (
+	waitBackoffInitial = 2 * time.Second
+	waitBackoffFactor  = 2.0
+	waitBackoffJitter  = 0.1
+	waitBackoffCap     = 30 * time.Second
+)
+
+// waitForConfig is the parsed `wait_for` block on k8s_manifest.
+type waitForConfig struct {
+	rollout    bool
+	conditions []waitCondition
+	fields     []waitField
+	timeout    time.Duration
+}
+
+type waitCondition struct {
+	conditionType string
+	status        string
+}
+
+type waitField struct {
+	jsonPath string
+	value    string
+}
+
+func parseWaitFor(d *schema.ResourceData) (*waitForConfig, error) {
+	raw, ok := d.GetOk("wait_for")
+	if !ok {
+		return nil, nil
+	}
+	list := raw.([]interface{})
+	if len(list) == 0 || list[0] == nil {
+		return nil, nil
+	}
+	block := list[0].(map[string]interface{})
+
+	timeout, err := time.ParseDuration(block["timeout"].(string))
+	if err != nil {
+		return nil, fmt.Errorf("parsing wait_for.timeout: %v", err)
+	}
+	wf := &waitForConfig{
+		rollout: block["rollout"].(bool),
+		timeout: timeout,
+	}
+
+	for _, raw := range block["condition"].([]interface{}) {
+		c := raw.(map[string]interface{})
+		wf.conditions = append(wf.conditions, waitCondition{
+			conditionType: c["type"].(string),
+			status:        c["status"].(string),
+		})
+	}
+	for _, raw := range block["field"].([]interface{}) {
+		f := raw.(map[string]interface{})
+		wf.fields = append(wf.fields, waitField{
+			jsonPath: f["json_path"].(string),
+			value:    f["value"].(string),
+		})
+	}
+	return wf, nil
+}
+
+// waitForObjects polls each of ids until wf's readiness checks are satisfied
+// or wf.timeout elapses, surfacing the last observed status in the error on
+// timeout so the condition that never became true is diagnosable.
+func waitForObjects(cfg *providerConfig, ids []objectID, wf *waitForConfig) error {
+	if wf == nil {
+		return nil
+	}
+	for _, id := range ids {
+		if err := waitForObject(cfg, id, wf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func waitForObject(cfg *providerConfig, id objectID, wf *waitForConfig) error {
+	gvk, err := id.groupVersionKind()
+	if err != nil {
+		return err
+	}
+	mapping, err := restMappingFor(cfg, gvk)
+	if err != nil {
+		return err
+	}
+	client := resourceInterface(cfg, mapping, id.Namespace)
+
+	ctx, cancel := context.WithTimeout(context.Background(), wf.timeout)
+	defer cancel()
+
+	backoff := wait.Backoff{
+		Duration: waitBackoffInitial,
+		Factor:   waitBackoffFactor,
+		Jitter:   waitBackoffJitter,
+		Cap:      waitBackoffCap,
+		Steps:    math.MaxInt32,
+	}
+
+	var lastObserved *unstructured.Unstructured
+	waitErr := wait.ExponentialBackoffWithContext(ctx, backoff, func(ctx context.Context) (bool, error) {
+		obj, err := client.Get(ctx, id.Name, metav1.GetOptions{})
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				return false, nil
+			}
+			return false, err
+		}
+		lastObserved = obj
+		return waitForConditionsMet(obj, wf)
+	})
+	if waitErr == nil {
+		return nil
+	}
+
+	status := "<not observed>"
+	if lastObserved != nil {
+		if b, err := marshalStatus(lastObserved); err == nil {
+			status = string(b)
+		}
+	}
+	return fmt.Errorf("timed out waiting for %s %q to become ready: %v; last observed status: %s", id.Kind, id.Name, waitErr, status)
+}
+
+func waitForConditionsMet(obj *unstructured.Unstructured, wf *waitForConfig) (bool, error) {
+	if wf.rollout {
+		ok, err := rolloutComplete(obj)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	for _, c := range wf.conditions {
+		if !conditionMet(obj, c) {
+			return false, nil
+		}
+	}
+	for _, f := range wf.fields {
+		ok, err := fieldMatches(obj, f)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// rolloutComplete mirrors the checks `kubectl rollout status` makes for the
+// workload kinds that support it. Kinds it doesn't know about are treated as
+// immediately complete, since they have no rollout concept.
+func rolloutComplete(obj *unstructured.Unstructured) (bool, error) {
+	switch obj.GetKind() {
+	case "Deployment", "StatefulSet", "DaemonSet":
+	default:
+		return true, nil
+	}
+
+	generation, _, _ := unstructured.NestedInt64(obj.Object, "metadata", "generation")
+	observedGeneration, _, _ := unstructured.NestedInt64(obj.Object, "status", "observedGeneration")
+	if observedGeneration < generation {
+		return false, nil
+	}
+
+	desired, found, _ := unstructured.NestedInt64(obj.Object, "spec", "replicas")
+	if !found {
+		desired = 1
+	}
+	updated, _, _ := unstructured.NestedInt64(obj.Object, "status", "updatedReplicas")
+	available, _, _ := unstructured.NestedInt64(obj.Object, "status", "availableReplicas")
+	if obj.GetKind() == "DaemonSet" {
+		desired, _, _ = unstructured.NestedInt64(obj.Object, "status", "desiredNumberScheduled")
+		updated, _, _ = unstructured.NestedInt64(obj.Object, "status", "updatedNumberScheduled")
+		available, _, _ = unstructured.NestedInt64(obj.Object, "status", "numberAvailable")
+	}
+	return updated >= desired && available >= desired, nil
+}
+
+func conditionMet(obj *unstructured.Unstructured, c waitCondition) bool {
+	conditions, found, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	if !found {
+		return false
+	}
+	for _, raw := range conditions {
+		condition, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if condition["type"] == c.conditionType && fmt.Sprintf("%v", condition["status"]) == c.status {
+			return true
+		}
+	}
+	return false
+}
+
+func fieldMatches(obj *unstructured.Unstructured, f waitField) (bool, error) {
+	jp := jsonpath.New("wait_for")
+	jp.AllowMissingKeys(true)
+	if err := jp.Parse(f.jsonPath); err != nil {
+		return false, fmt.Errorf("parsing wait_for field json_path %q: %v", f.jsonPath, err)
+	}
+
+	var buf bytes.Buffer
+	if err := jp.Execute(&buf, obj.Object); err != nil {
+		return false, nil
+	}
+	return buf.String() == f.value, nil
+}
+
+func marshalStatus(obj *unstructured.Unstructured) ([]byte, error) {
+	status, found, _ := unstructured.NestedFieldNoCopy(obj.Object, "status")
+	if !found {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(status)
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func deployment(generation, observedGeneration, replicas, updated, available int64) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata": map[string]interface{}{
+			"generation": generation,
+		},
+		"spec": map[string]interface{}{
+			"replicas": replicas,
+		},
+		"status": map[string]interface{}{
+			"observedGeneration": observedGeneration,
+			"updatedReplicas":    updated,
+			"availableReplicas":  available,
+		},
+	}}
+}
+
+func TestRolloutComplete(t *testing.T) {
+	cases := []struct {
+		name string
+		obj  *unstructured.Unstructured
+		want bool
+	}{
+		{"kind without a rollout concept is always complete", &unstructured.Unstructured{Object: map[string]interface{}{"kind": "ConfigMap"}}, true},
+		{"stale observedGeneration", deployment(2, 1, 3, 3, 3), false},
+		{"not enough updated replicas yet", deployment(1, 1, 3, 2, 3), false},
+		{"not enough available replicas yet", deployment(1, 1, 3, 3, 2), false},
+		{"fully rolled out", deployment(1, 1, 3, 3, 3), true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := rolloutComplete(tc.obj)
+			if err != nil {
+				t.Fatalf("rolloutComplete: %v", err)
+			}
+			if got != tc.want {
+				t.Fatalf("rolloutComplete() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestConditionMet(t *testing.T) {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"status": map[string]interface{}{
+			"conditions": []interface{}{
+				map[string]interface{}{"type": "Available", "status": "True"},
+				map[string]interface{}{"type": "Progressing", "status": "False"},
+			},
+		},
+	}}
+
+	if !conditionMet(obj, waitCondition{conditionType: "Available", status: "True"}) {
+		t.Fatalf("expected Available=True to be met")
+	}
+	if conditionMet(obj, waitCondition{conditionType: "Progressing", status: "True"}) {
+		t.Fatalf("expected Progressing=True to not be met")
+	}
+	if conditionMet(obj, waitCondition{conditionType: "DoesNotExist", status: "True"}) {
+		t.Fatalf("expected a condition type absent from status.conditions to not be met")
+	}
+}
+
+func TestConditionMetNoConditions(t *testing.T) {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	if conditionMet(obj, waitCondition{conditionType: "Available", status: "True"}) {
+		t.Fatalf("expected no status.conditions to never match")
+	}
+}
+
+func TestFieldMatches(t *testing.T) {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"status": map[string]interface{}{
+			"phase": "Running",
+		},
+	}}
+
+	ok, err := fieldMatches(obj, waitField{jsonPath: "{.status.phase}", value: "Running"})
+	if err != nil {
+		t.Fatalf("fieldMatches: %v", err)
+	}
+	if !ok {
+		t.Fatalf("expected status.phase=Running to match")
+	}
+
+	ok, err = fieldMatches(obj, waitField{jsonPath: "{.status.phase}", value: "Pending"})
+	if err != nil {
+		t.Fatalf("fieldMatches: %v", err)
+	}
+	if ok {
+		t.Fatalf("expected status.phase=Running to not match \"Pending\"")
+	}
+
+	ok, err = fieldMatches(obj, waitField{jsonPath: "{.status.missing}", value: ""})
+	if err != nil {
+		t.Fatalf("fieldMatches on a missing key should not error: %v", err)
+	}
+	if !ok {
+		t.Fatalf("expected a missing key rendering empty to match an empty wanted value")
+	}
+
+	if _, err := fieldMatches(obj, waitField{jsonPath: "{.status[", value: ""}); err == nil {
+		t.Fatalf("expected an error parsing a malformed json_path")
+	}
+}